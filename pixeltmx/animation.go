@@ -0,0 +1,134 @@
+package pixeltmx
+
+import (
+	"time"
+
+	"github.com/elliotmr/tmx"
+)
+
+// animFrame is a single frame of a tile animation: the local tile id to
+// display and how long to display it for.
+type animFrame struct {
+	tileID   uint32
+	duration time.Duration
+}
+
+// minFrameDuration is the shortest duration a frame is allowed to have.
+// Tiled doesn't permit a frame duration of 0, but hand-edited or malformed
+// TMX data might; without a floor, advance's rollover loop would never
+// make progress past a zero-duration frame and spin forever.
+const minFrameDuration = time.Millisecond
+
+// tileAnimation tracks playback of one animated tile definition. The clock
+// is shared by every placed instance of that tile, matching how Tiled
+// itself drives tile animation.
+type tileAnimation struct {
+	firstGID uint32
+	frames   []animFrame
+	elapsed  time.Duration
+	index    int
+}
+
+// currentGID returns the GID that should currently be rendered in place of
+// the animation's base tile.
+func (a *tileAnimation) currentGID() uint32 {
+	return a.firstGID + a.frames[a.index].tileID
+}
+
+// advance moves the animation clock forward by dt, rolling over to
+// subsequent frames (and wrapping back to the first) as their durations
+// elapse.
+func (a *tileAnimation) advance(dt time.Duration) {
+	if len(a.frames) == 0 {
+		return
+	}
+	a.elapsed += dt
+	for a.elapsed >= a.frames[a.index].duration {
+		a.elapsed -= a.frames[a.index].duration
+		a.index = (a.index + 1) % len(a.frames)
+	}
+}
+
+// reset rewinds the animation to its first frame.
+func (a *tileAnimation) reset() {
+	a.elapsed = 0
+	a.index = 0
+}
+
+// seek advances the animation to whatever frame would be showing at time t
+// after a reset, without needing to tick through every intermediate frame.
+func (a *tileAnimation) seek(t time.Duration) {
+	a.reset()
+	if len(a.frames) == 0 {
+		return
+	}
+	var total time.Duration
+	for _, f := range a.frames {
+		total += f.duration
+	}
+	if total > 0 {
+		t %= total
+	}
+	a.advance(t)
+}
+
+// loadAnimations walks a tileset's tile definitions and records any
+// declared <animation> as a tileAnimation keyed by the tile's GID, so
+// Resources.Update can advance it and fillTileAndMod can resolve it.
+func (r *Resources) loadAnimations(set *tmx.TileSet) {
+	for _, tile := range set.Tiles {
+		if tile.Animation == nil || len(tile.Animation.Frames) == 0 {
+			continue
+		}
+		frames := make([]animFrame, len(tile.Animation.Frames))
+		for i, f := range tile.Animation.Frames {
+			duration := time.Duration(f.Duration) * time.Millisecond
+			if duration < minFrameDuration {
+				duration = minFrameDuration
+			}
+			frames[i] = animFrame{
+				tileID:   f.TileID,
+				duration: duration,
+			}
+		}
+		gid := set.FirstGID + tile.ID
+		r.animations[gid] = &tileAnimation{
+			firstGID: set.FirstGID,
+			frames:   frames,
+		}
+	}
+}
+
+// Update advances every animated tile's clock by dt. It should be called
+// once per frame before rendering.
+func (r *Resources) Update(dt time.Duration) {
+	for _, a := range r.animations {
+		a.advance(dt)
+	}
+}
+
+// ResetAnimation rewinds the animated tile at gid (the tile's base GID, not
+// one of its animation frames) back to its first frame. It is a no-op if
+// gid is not animated.
+func (r *Resources) ResetAnimation(gid uint32) {
+	if a, ok := r.animations[gid]; ok {
+		a.reset()
+	}
+}
+
+// SeekAnimation moves the animated tile at gid to the frame it would be
+// showing at time t after a reset. It is a no-op if gid is not animated.
+func (r *Resources) SeekAnimation(gid uint32, t time.Duration) {
+	if a, ok := r.animations[gid]; ok {
+		a.seek(t)
+	}
+}
+
+// resolveGID returns the GID that should actually be rendered for gid,
+// following its animation's current frame if it has one.
+func (r *Resources) resolveGID(gid uint32) uint32 {
+	if a, ok := r.animations[gid]; ok {
+		return a.currentGID()
+	}
+	return gid
+}
@@ -0,0 +1,79 @@
+package pixeltmx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elliotmr/tmx"
+)
+
+func TestTileAnimationAdvanceAndSeek(t *testing.T) {
+	a := &tileAnimation{
+		firstGID: 100,
+		frames: []animFrame{
+			{tileID: 0, duration: 100 * time.Millisecond},
+			{tileID: 1, duration: 200 * time.Millisecond},
+			{tileID: 2, duration: 50 * time.Millisecond},
+		},
+	}
+
+	a.advance(50 * time.Millisecond)
+	if got := a.currentGID(); got != 100 {
+		t.Fatalf("expected frame 0 (gid 100), got %d", got)
+	}
+
+	a.advance(60 * time.Millisecond) // elapsed 110ms total -> rolls into frame 1
+	if got := a.currentGID(); got != 101 {
+		t.Fatalf("expected frame 1 (gid 101), got %d", got)
+	}
+
+	a.advance(250 * time.Millisecond) // rolls through the rest of frame 1 and all of frame 2, wraps to frame 0
+	if got := a.currentGID(); got != 100 {
+		t.Fatalf("expected wrap to frame 0 (gid 100), got %d", got)
+	}
+
+	a.seek(110 * time.Millisecond)
+	if got := a.currentGID(); got != 101 {
+		t.Fatalf("seek: expected frame 1 (gid 101), got %d", got)
+	}
+}
+
+func TestLoadAnimationsFloorsZeroDuration(t *testing.T) {
+	set := &tmx.TileSet{
+		FirstGID: 1,
+		Tiles: []tmx.Tile{
+			{
+				ID: 0,
+				Animation: &tmx.Animation{
+					Frames: []tmx.Frame{
+						{TileID: 0, Duration: 0},
+						{TileID: 1, Duration: 0},
+					},
+				},
+			},
+		},
+	}
+	r := &Resources{animations: make(map[uint32]*tileAnimation)}
+	r.loadAnimations(set)
+
+	anim, ok := r.animations[1]
+	if !ok {
+		t.Fatal("expected animation to be registered for gid 1")
+	}
+	for _, f := range anim.frames {
+		if f.duration <= 0 {
+			t.Fatalf("expected frame duration to be floored above zero, got %v", f.duration)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		anim.advance(time.Second)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("advance appears to have entered an infinite loop on zero-duration frames")
+	}
+}
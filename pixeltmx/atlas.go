@@ -0,0 +1,135 @@
+package pixeltmx
+
+import (
+	"image/color"
+
+	"github.com/faiface/pixel"
+)
+
+// atlasPageSize is the maximum width/height of a single packed atlas page.
+// Images that would overflow it start a new page instead.
+const atlasPageSize = 2048
+
+// placement records where a source image ended up after packing: which
+// page it landed on and the sub-rectangle of that page it occupies.
+type placement struct {
+	page int
+	rect pixel.Rect
+}
+
+// Atlas packs a growing set of named source pictures into one or more
+// fixed-size pages, shelf-style, so many small tileset images can be drawn
+// from a single texture per page. Pack is reentrant: calling it again with
+// previously-seen sources is a no-op, and new sources are appended to the
+// existing pages (or a fresh page) without disturbing earlier placements.
+type Atlas struct {
+	pages      []*pixel.PictureData
+	shelfX     []float64
+	shelfY     []float64
+	shelfH     []float64
+	placements map[string]placement
+}
+
+// NewAtlas returns an empty Atlas ready to Pack images into.
+func NewAtlas() *Atlas {
+	return &Atlas{placements: make(map[string]placement)}
+}
+
+// Picture returns the packed picture for the given page index.
+func (a *Atlas) Picture(page int) pixel.Picture {
+	return a.pages[page]
+}
+
+// Placement returns the page and packed rectangle for a previously packed
+// source, and whether it has been packed at all.
+func (a *Atlas) Placement(source string) (int, pixel.Rect, bool) {
+	p, ok := a.placements[source]
+	return p.page, p.rect, ok
+}
+
+// Pack packs any image in images whose key has not already been placed.
+// Already-placed sources are left untouched, so Pack can safely be called
+// again as new tilesets or templates are loaded. Pictures that are not
+// *pixel.PictureData are skipped and get no placement.
+func (a *Atlas) Pack(images map[string]pixel.Picture) error {
+	for source, pic := range images {
+		if _, ok := a.placements[source]; ok {
+			continue
+		}
+		data, ok := pic.(*pixel.PictureData)
+		if !ok {
+			continue
+		}
+		a.placements[source] = a.place(data)
+	}
+	return nil
+}
+
+// place finds room for data on an existing page (via shelf packing) or
+// opens a new page if none has room, then blits the pixels in and records
+// the resulting placement.
+func (a *Atlas) place(data *pixel.PictureData) placement {
+	w := data.Rect.W()
+	h := data.Rect.H()
+	for page := range a.pages {
+		if rect, ok := a.tryShelf(page, w, h); ok {
+			a.blit(page, rect, data)
+			return placement{page: page, rect: rect}
+		}
+	}
+	page := a.newPage()
+	rect, ok := a.tryShelf(page, w, h)
+	if !ok {
+		// The image is larger than a full page; give it the page to itself.
+		rect = pixel.R(0, 0, w, h)
+		a.shelfY[page] = atlasPageSize
+	}
+	a.blit(page, rect, data)
+	return placement{page: page, rect: rect}
+}
+
+func (a *Atlas) newPage() int {
+	a.pages = append(a.pages, pixel.MakePictureData(pixel.R(0, 0, atlasPageSize, atlasPageSize)))
+	a.shelfX = append(a.shelfX, 0)
+	a.shelfY = append(a.shelfY, 0)
+	a.shelfH = append(a.shelfH, 0)
+	return len(a.pages) - 1
+}
+
+// tryShelf attempts to reserve a w x h rectangle on page's current shelf,
+// starting a new shelf row (or failing outright) if it doesn't fit.
+func (a *Atlas) tryShelf(page int, w, h float64) (pixel.Rect, bool) {
+	if a.shelfX[page]+w > atlasPageSize {
+		a.shelfX[page] = 0
+		a.shelfY[page] += a.shelfH[page]
+		a.shelfH[page] = 0
+	}
+	if a.shelfY[page]+h > atlasPageSize {
+		return pixel.Rect{}, false
+	}
+	rect := pixel.R(a.shelfX[page], a.shelfY[page], a.shelfX[page]+w, a.shelfY[page]+h)
+	a.shelfX[page] += w
+	if h > a.shelfH[page] {
+		a.shelfH[page] = h
+	}
+	return rect, true
+}
+
+// blit copies src's pixels into page at dst, preserving the bottom-left
+// origin convention pixel.PictureData uses throughout.
+func (a *Atlas) blit(page int, dst pixel.Rect, src *pixel.PictureData) {
+	out := a.pages[page]
+	for y := 0; y < int(src.Rect.H()); y++ {
+		for x := 0; x < int(src.Rect.W()); x++ {
+			srcAt := pixel.V(src.Rect.Min.X+float64(x), src.Rect.Min.Y+float64(y))
+			dstAt := pixel.V(dst.Min.X+float64(x), dst.Min.Y+float64(y))
+			var c color.RGBA
+			if idx := src.Index(srcAt); idx >= 0 && idx < len(src.Pix) {
+				c = src.Pix[idx]
+			}
+			if idx := out.Index(dstAt); idx >= 0 && idx < len(out.Pix) {
+				out.Pix[idx] = c
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package pixeltmx
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func solidPicture(w, h int, c color.RGBA) *pixel.PictureData {
+	data := pixel.MakePictureData(pixel.R(0, 0, float64(w), float64(h)))
+	for i := range data.Pix {
+		data.Pix[i] = c
+	}
+	return data
+}
+
+func TestAtlasPackDistinctPlacements(t *testing.T) {
+	red := solidPicture(8, 8, color.RGBA{R: 255, A: 255})
+	blue := solidPicture(8, 8, color.RGBA{B: 255, A: 255})
+
+	atlas := NewAtlas()
+	if err := atlas.Pack(map[string]pixel.Picture{"red": red, "blue": blue}); err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+
+	redPage, redRect, ok := atlas.Placement("red")
+	if !ok {
+		t.Fatal("expected red to be placed")
+	}
+	bluePage, blueRect, ok := atlas.Placement("blue")
+	if !ok {
+		t.Fatal("expected blue to be placed")
+	}
+	if redPage == bluePage && redRect == blueRect {
+		t.Fatalf("red and blue landed at the same placement: %v", redRect)
+	}
+
+	page := atlas.Picture(redPage).(*pixel.PictureData)
+	if got := page.Pix[page.Index(redRect.Center())]; got != (color.RGBA{R: 255, A: 255}) {
+		t.Fatalf("expected red pixel at %v, got %v", redRect.Center(), got)
+	}
+
+	page = atlas.Picture(bluePage).(*pixel.PictureData)
+	if got := page.Pix[page.Index(blueRect.Center())]; got != (color.RGBA{B: 255, A: 255}) {
+		t.Fatalf("expected blue pixel at %v, got %v", blueRect.Center(), got)
+	}
+}
+
+func TestAtlasPackIsReentrant(t *testing.T) {
+	red := solidPicture(4, 4, color.RGBA{R: 255, A: 255})
+	atlas := NewAtlas()
+	images := map[string]pixel.Picture{"red": red}
+	if err := atlas.Pack(images); err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	_, firstRect, _ := atlas.Placement("red")
+
+	images["green"] = solidPicture(4, 4, color.RGBA{G: 255, A: 255})
+	if err := atlas.Pack(images); err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+
+	_, secondRect, _ := atlas.Placement("red")
+	if firstRect != secondRect {
+		t.Fatalf("re-packing moved an already-placed image: %v -> %v", firstRect, secondRect)
+	}
+	if _, _, ok := atlas.Placement("green"); !ok {
+		t.Fatal("expected green to be placed after a second Pack call")
+	}
+}
@@ -0,0 +1,93 @@
+package pixeltmx
+
+import (
+	"github.com/elliotmr/tmx"
+	"github.com/faiface/pixel"
+)
+
+// Batch accumulates the triangle data for every tile in a layer, grouped by
+// atlas page, so the whole layer can be submitted to the GPU in one draw
+// call per page instead of one per tile.
+type Batch struct {
+	pages map[int]*pixel.TrianglesData
+	order []int
+}
+
+// NewBatch returns an empty Batch ready to accumulate tiles for a layer.
+func NewBatch() *Batch {
+	return &Batch{pages: make(map[int]*pixel.TrianglesData)}
+}
+
+// Reset empties every page's accumulated triangles so the Batch can be
+// reused for the next frame without reallocating its backing storage.
+func (b *Batch) Reset() {
+	for _, data := range b.pages {
+		*data = (*data)[:0]
+	}
+}
+
+func (b *Batch) pageData(page int) *pixel.TrianglesData {
+	data, ok := b.pages[page]
+	if !ok {
+		data = &pixel.TrianglesData{}
+		b.pages[page] = data
+		b.order = append(b.order, page)
+	}
+	return data
+}
+
+// AppendTile adds the triangle data for the tile at (col, row) to the
+// batch, applying its flip flags and the map's orientation-aware
+// projection the same way fillTileAndMod does for the unbatched path.
+func (r *Resources) AppendTile(b *Batch, tile tmx.TileInstance, col, row int, rbga pixel.RGBA) {
+	gid := r.resolveGID(tile.GID())
+	entry, exists := r.entries[gid]
+	if !exists {
+		return
+	}
+	data, ok := entry.data.Copy().(*pixel.TrianglesData)
+	if !ok {
+		return
+	}
+
+	if tile.FlippedDiagonally() {
+		for i := range *data {
+			(*data)[i].Position = diagonalFlipMatrix.Project((*data)[i].Position)
+		}
+	}
+	if tile.FlippedHorizontally() {
+		for i := range *data {
+			(*data)[i].Position = horizontalFlipMatrix.Project((*data)[i].Position)
+		}
+	}
+	if tile.FlippedVertically() {
+		for i := range *data {
+			(*data)[i].Position = verticalFlipMatrix.Project((*data)[i].Position)
+		}
+	}
+
+	center := r.TileToScreen(col, row)
+	for i := range *data {
+		(*data)[i].Position = (*data)[i].Position.Add(center)
+		(*data)[i].Color = rbga
+	}
+
+	page := b.pageData(entry.page)
+	*page = append(*page, (*data)...)
+}
+
+// Draw submits the batch's accumulated triangles to t, one draw call per
+// atlas page touched by the layer.
+func (r *Resources) Draw(t pixel.Target, b *Batch) {
+	for _, page := range b.order {
+		data := b.pages[page]
+		if len(*data) == 0 {
+			continue
+		}
+		tris := t.MakeTriangles(data)
+		if tp, ok := tris.(pixel.TargetPicture); ok {
+			tris = tp.SetPicture(r.atlas.Picture(page))
+		}
+		tris.Draw()
+	}
+}
@@ -0,0 +1,216 @@
+package pixeltmx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/elliotmr/tmx"
+	"github.com/faiface/pixel"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// chunkKey identifies one chunk of an infinite layer by its origin tile
+// coordinate.
+type chunkKey struct {
+	x, y int
+}
+
+// renderedChunk is a decoded chunk's tiles, already batched, along with the
+// screen-space bounds used to cull it against the current view.
+type renderedChunk struct {
+	bounds pixel.Rect
+	batch  *Batch
+}
+
+// ChunkedLayer renders one infinite-map tile layer a chunk at a time,
+// decoding and batching each chunk the first time it becomes visible and
+// reusing that work on subsequent frames. This keeps per-frame cost
+// proportional to what's on screen rather than the map's total size.
+type ChunkedLayer struct {
+	r     *Resources
+	layer *tmx.Layer
+	built map[chunkKey]*renderedChunk
+}
+
+// NewChunkedLayer prepares layer, which must be an infinite-map layer
+// (its Data holds <chunk> children rather than a flat tile grid), for
+// culled, chunk-at-a-time rendering.
+func (r *Resources) NewChunkedLayer(layer *tmx.Layer) *ChunkedLayer {
+	return &ChunkedLayer{r: r, layer: layer, built: make(map[chunkKey]*renderedChunk)}
+}
+
+// Draw builds (on first use) and submits only the chunks that intersect
+// view.
+func (c *ChunkedLayer) Draw(t pixel.Target, view pixel.Rect) error {
+	for _, chunk := range c.layer.Data.Chunks {
+		key := chunkKey{x: chunk.X, y: chunk.Y}
+		rendered, ok := c.built[key]
+		if !ok {
+			if !rectsIntersect(c.chunkBounds(chunk), view) {
+				continue
+			}
+			var err error
+			rendered, err = c.buildChunk(chunk)
+			if err != nil {
+				return errors.Wrapf(err, "chunk (%d,%d)", chunk.X, chunk.Y)
+			}
+			c.built[key] = rendered
+		}
+		if !rectsIntersect(rendered.bounds, view) {
+			continue
+		}
+		c.r.Draw(t, rendered.batch)
+	}
+	return nil
+}
+
+// buildChunk decodes one chunk's tile data and batches it into a Batch,
+// recording the chunk's approximate screen-space bounds for culling.
+func (c *ChunkedLayer) buildChunk(chunk tmx.Chunk) (*renderedChunk, error) {
+	tiles, err := decodeChunk(c.layer.Data.Encoding, c.layer.Data.Compression, chunk)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := NewBatch()
+	for i, tile := range tiles {
+		if tile.GID() == 0 {
+			continue
+		}
+		col := chunk.X + i%chunk.Width
+		row := chunk.Y + i/chunk.Width
+		c.r.AppendTile(batch, tile, col, row, pixel.Alpha(1))
+	}
+
+	return &renderedChunk{bounds: c.chunkBounds(chunk), batch: batch}, nil
+}
+
+// chunkBounds returns a screen-space rectangle covering every tile in
+// chunk, padded by one tile so partially-visible edge tiles aren't culled.
+func (c *ChunkedLayer) chunkBounds(chunk tmx.Chunk) pixel.Rect {
+	corners := []pixel.Vec{
+		c.r.TileToScreen(chunk.X, chunk.Y),
+		c.r.TileToScreen(chunk.X+chunk.Width-1, chunk.Y),
+		c.r.TileToScreen(chunk.X, chunk.Y+chunk.Height-1),
+		c.r.TileToScreen(chunk.X+chunk.Width-1, chunk.Y+chunk.Height-1),
+	}
+	minX, minY := corners[0].X, corners[0].Y
+	maxX, maxY := corners[0].X, corners[0].Y
+	for _, p := range corners[1:] {
+		minX = math.Min(minX, p.X)
+		minY = math.Min(minY, p.Y)
+		maxX = math.Max(maxX, p.X)
+		maxY = math.Max(maxY, p.Y)
+	}
+	pad := float64(c.r.geometry.tileWidth)
+	return pixel.R(minX-pad, minY-pad, maxX+pad, maxY+pad)
+}
+
+func rectsIntersect(a, b pixel.Rect) bool {
+	return a.Min.X < b.Max.X && a.Max.X > b.Min.X && a.Min.Y < b.Max.Y && a.Max.Y > b.Min.Y
+}
+
+// decodeChunk turns one <chunk>'s raw payload into a flat, row-major slice
+// of tile instances, honoring the csv, base64, base64+gzip, base64+zlib,
+// and base64+zstd encodings Tiled can emit for infinite maps.
+func decodeChunk(encoding, compression string, chunk tmx.Chunk) ([]tmx.TileInstance, error) {
+	count := chunk.Width * chunk.Height
+	switch encoding {
+	case "csv":
+		return decodeCSVChunk(chunk.Data, count)
+	case "base64":
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(chunk.Data))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid base64 chunk data")
+		}
+		raw, err = decompressChunk(compression, raw, int64(count)*4)
+		if err != nil {
+			return nil, err
+		}
+		return decodeRawChunk(raw, count)
+	default:
+		return nil, errors.Errorf("unsupported chunk encoding %q", encoding)
+	}
+}
+
+// decompressChunk inflates raw and returns at most wantBytes+1 bytes of
+// decompressed output: one byte past what a well-formed chunk could need,
+// so oversized output still fails decodeRawChunk's length check instead of
+// letting a decompression bomb exhaust memory.
+func decompressChunk(compression string, raw []byte, wantBytes int64) ([]byte, error) {
+	switch compression {
+	case "":
+		return raw, nil
+	case "gzip":
+		rdr, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid gzip chunk data")
+		}
+		defer rdr.Close()
+		return io.ReadAll(io.LimitReader(rdr, wantBytes+1))
+	case "zlib":
+		rdr, err := zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid zlib chunk data")
+		}
+		defer rdr.Close()
+		return io.ReadAll(io.LimitReader(rdr, wantBytes+1))
+	case "zstd":
+		dec, err := zstd.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid zstd chunk data")
+		}
+		defer dec.Close()
+		return io.ReadAll(io.LimitReader(dec, wantBytes+1))
+	default:
+		return nil, errors.Errorf("unsupported chunk compression %q", compression)
+	}
+}
+
+func decodeRawChunk(raw []byte, count int) ([]tmx.TileInstance, error) {
+	if len(raw) != count*4 {
+		return nil, errors.Errorf("chunk data length %d does not match expected tile count %d", len(raw), count)
+	}
+	tiles := make([]tmx.TileInstance, count)
+	for i := range tiles {
+		gid := binary.LittleEndian.Uint32(raw[i*4 : i*4+4])
+		tiles[i] = tmx.TileInstance(gid)
+	}
+	return tiles, nil
+}
+
+func decodeCSVChunk(data string, count int) ([]tmx.TileInstance, error) {
+	reader := csv.NewReader(strings.NewReader(strings.TrimSpace(data)))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid csv chunk data")
+	}
+	tiles := make([]tmx.TileInstance, 0, count)
+	for _, row := range records {
+		for _, field := range row {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			gid, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid csv tile gid %q", field)
+			}
+			tiles = append(tiles, tmx.TileInstance(gid))
+		}
+	}
+	if len(tiles) != count {
+		return nil, errors.Errorf("csv chunk has %d tiles, expected %d", len(tiles), count)
+	}
+	return tiles, nil
+}
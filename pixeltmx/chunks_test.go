@@ -0,0 +1,88 @@
+package pixeltmx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+
+	"github.com/elliotmr/tmx"
+	"github.com/klauspost/compress/zstd"
+)
+
+func rawTileBytes(gids []uint32) []byte {
+	buf := make([]byte, len(gids)*4)
+	for i, gid := range gids {
+		binary.LittleEndian.PutUint32(buf[i*4:], gid)
+	}
+	return buf
+}
+
+func TestDecodeChunkEncodings(t *testing.T) {
+	gids := []uint32{1, 2, 3, 4}
+	raw := rawTileBytes(gids)
+
+	gzipBuf := &bytes.Buffer{}
+	gw := gzip.NewWriter(gzipBuf)
+	gw.Write(raw)
+	gw.Close()
+
+	zlibBuf := &bytes.Buffer{}
+	zw := zlib.NewWriter(zlibBuf)
+	zw.Write(raw)
+	zw.Close()
+
+	zstdBuf := &bytes.Buffer{}
+	zsw, err := zstd.NewWriter(zstdBuf)
+	if err != nil {
+		t.Fatalf("zstd.NewWriter: %v", err)
+	}
+	zsw.Write(raw)
+	zsw.Close()
+
+	cases := []struct {
+		name        string
+		encoding    string
+		compression string
+		data        string
+	}{
+		{"csv", "csv", "", "1,2,3,4"},
+		{"base64", "base64", "", base64.StdEncoding.EncodeToString(raw)},
+		{"base64+gzip", "base64", "gzip", base64.StdEncoding.EncodeToString(gzipBuf.Bytes())},
+		{"base64+zlib", "base64", "zlib", base64.StdEncoding.EncodeToString(zlibBuf.Bytes())},
+		{"base64+zstd", "base64", "zstd", base64.StdEncoding.EncodeToString(zstdBuf.Bytes())},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			chunk := tmx.Chunk{X: 0, Y: 0, Width: 2, Height: 2, Data: c.data}
+			tiles, err := decodeChunk(c.encoding, c.compression, chunk)
+			if err != nil {
+				t.Fatalf("decodeChunk: %v", err)
+			}
+			if len(tiles) != len(gids) {
+				t.Fatalf("expected %d tiles, got %d", len(gids), len(tiles))
+			}
+			for i, want := range gids {
+				if got := tiles[i].GID(); got != want {
+					t.Errorf("tile %d: expected gid %d, got %d", i, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeChunkRejectsOversizedDecompressedData(t *testing.T) {
+	oversized := bytes.Repeat([]byte{0}, 4096)
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	gw.Write(oversized)
+	gw.Close()
+
+	chunk := tmx.Chunk{X: 0, Y: 0, Width: 2, Height: 2, Data: base64.StdEncoding.EncodeToString(buf.Bytes())}
+	if _, err := decodeChunk("base64", "gzip", chunk); err == nil {
+		t.Fatal("expected decodeChunk to reject decompressed data larger than the chunk's declared tile count")
+	}
+}
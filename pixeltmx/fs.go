@@ -0,0 +1,89 @@
+package pixeltmx
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ResourceLoader resolves and opens the files a TMX map references —
+// tileset and layer images, templates, and anything else loaded relative
+// to the map's own location. Implementing it over an embed.FS, an
+// in-memory tree, or an archive-backed filesystem lets callers load map
+// resources from somewhere other than the OS filesystem.
+type ResourceLoader interface {
+	fs.FS
+	// Resolve turns a `source` attribute from the TMX file (a path
+	// relative to the map, or occasionally an absolute one) into the name
+	// that should be passed to Open.
+	Resolve(source string) string
+}
+
+// dirLoader is the ResourceLoader backing the original path-based
+// LoadResources API: it serves files from an OS directory.
+type dirLoader struct {
+	fs.FS
+	root string
+}
+
+// NewDirLoader returns a ResourceLoader that reads resources from the OS
+// directory at root. An empty root means the current working directory,
+// matching LoadResources' historical default.
+func NewDirLoader(root string) ResourceLoader {
+	if root == "" {
+		root = "."
+	}
+	return &dirLoader{FS: os.DirFS(root), root: root}
+}
+
+// Open serves relative names from the loader's root directory, and falls
+// back to raw os.Open for absolute paths (fs.FS rejects those outright).
+// Names containing ".." are resolved against the root and allowed only if
+// the result stays within it: "maps/../tilesets/foo.png" opens
+// "tilesets/foo.png" under the root, while "../tilesets/foo.png", which
+// climbs above the root, is rejected.
+func (d *dirLoader) Open(name string) (fs.File, error) {
+	if filepath.IsAbs(name) {
+		return os.Open(name)
+	}
+	if !strings.Contains(name, "..") {
+		return d.FS.Open(name)
+	}
+	absRoot, err := filepath.Abs(d.root)
+	if err != nil {
+		return nil, err
+	}
+	joined := filepath.Join(absRoot, name)
+	if !pathWithinRoot(absRoot, joined) {
+		return nil, errors.Errorf("resource %q escapes loader root %q", name, d.root)
+	}
+	return os.Open(joined)
+}
+
+// pathWithinRoot reports whether path is root itself or a descendant of it.
+func pathWithinRoot(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+func (d *dirLoader) Resolve(source string) string {
+	if filepath.IsAbs(source) {
+		return filepath.Clean(source)
+	}
+	return filepath.ToSlash(filepath.Clean(source))
+}
+
+func (r *Resources) openResource(source string) (fs.File, string, error) {
+	resolved := r.loader.Resolve(source)
+	f, err := r.loader.Open(resolved)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "unable to open resource %q", source)
+	}
+	return f, resolved, nil
+}
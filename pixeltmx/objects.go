@@ -0,0 +1,247 @@
+package pixeltmx
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/elliotmr/tmx"
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/text"
+	"github.com/pkg/errors"
+)
+
+// templateEntry is a parsed <template> file: the object it defines and,
+// for tile objects, the tileset that object's gid belongs to.
+type templateEntry struct {
+	object  *tmx.Object
+	tileSet *tmx.TileSet
+}
+
+// loadTemplate parses and caches the template referenced by source,
+// loading its tileset image (if any) the same way a map-level tileset is
+// loaded. Repeated references to the same template are a no-op.
+func (r *Resources) loadTemplate(source string) error {
+	resolved := r.loader.Resolve(source)
+	if _, ok := r.templates[resolved]; ok {
+		return nil
+	}
+	f, resolved, err := r.openResource(source)
+	if err != nil {
+		return errors.Wrap(err, "unable to open object template")
+	}
+	defer f.Close()
+	tmpl, err := tmx.ParseTemplate(f)
+	if err != nil {
+		return errors.Wrap(err, "unable to parse object template")
+	}
+	entry := &templateEntry{object: tmpl.Object}
+	if tmpl.TileSet != nil {
+		if err := r.loadTileSet(tmpl.TileSet); err != nil {
+			return errors.Wrap(err, "unable to load template tileset")
+		}
+		entry.tileSet = tmpl.TileSet
+	}
+	r.templates[resolved] = entry
+	return nil
+}
+
+// resolveObject merges obj with the object defined by its template, if it
+// references one. Fields obj itself sets always win, matching how Tiled
+// treats template overrides: the placed instance's id, position, rotation,
+// visibility, and properties are always its own, while shape/gid/size fall
+// back to the template when the instance doesn't specify them.
+func (r *Resources) resolveObject(obj *tmx.Object) *tmx.Object {
+	if obj.Template == "" {
+		return obj
+	}
+	tmpl, ok := r.templates[r.loader.Resolve(obj.Template)]
+	if !ok || tmpl.object == nil {
+		return obj
+	}
+	merged := *tmpl.object
+	if obj.GID != 0 {
+		merged.GID = obj.GID
+	}
+	if obj.Width != 0 {
+		merged.Width = obj.Width
+	}
+	if obj.Height != 0 {
+		merged.Height = obj.Height
+	}
+	if obj.Ellipse != nil {
+		merged.Ellipse = obj.Ellipse
+	}
+	if obj.Polygon != nil {
+		merged.Polygon = obj.Polygon
+	}
+	if obj.Polyline != nil {
+		merged.Polyline = obj.Polyline
+	}
+	merged.ID = obj.ID
+	merged.Name = obj.Name
+	merged.X = obj.X
+	merged.Y = obj.Y
+	merged.Rotation = obj.Rotation
+	merged.Visible = obj.Visible
+	merged.Properties = obj.Properties
+	return &merged
+}
+
+// ObjectRenderer draws a single resolved object. Game code can install a
+// custom implementation with Resources.SetObjectRenderer to change how
+// tile objects, shapes, and text are presented.
+type ObjectRenderer interface {
+	DrawTile(r *Resources, t pixel.Target, obj *tmx.Object, tint pixel.RGBA)
+	DrawShape(r *Resources, t pixel.Target, obj *tmx.Object, tint pixel.RGBA)
+	DrawText(r *Resources, t pixel.Target, obj *tmx.Object, tint pixel.RGBA)
+}
+
+// SetObjectRenderer installs the ObjectRenderer used by DrawObject.
+func (r *Resources) SetObjectRenderer(o ObjectRenderer) {
+	r.renderer = o
+}
+
+// SetTextAtlas installs the font atlas the default ObjectRenderer uses to
+// draw text objects. Without one, text objects are skipped.
+func (r *Resources) SetTextAtlas(a *text.Atlas) {
+	r.textAtlas = a
+}
+
+// DrawObject resolves obj against its template (if any) and dispatches it
+// to the installed ObjectRenderer based on whether it's a tile, text, or
+// shape object.
+func (r *Resources) DrawObject(t pixel.Target, obj *tmx.Object) {
+	resolved := r.resolveObject(obj)
+	if !resolved.Visible {
+		return
+	}
+	tint := objectTint(resolved)
+	switch {
+	case resolved.GID != 0:
+		r.renderer.DrawTile(r, t, resolved, tint)
+	case resolved.Text != nil:
+		r.renderer.DrawText(r, t, resolved, tint)
+	default:
+		r.renderer.DrawShape(r, t, resolved, tint)
+	}
+}
+
+// defaultObjectRenderer is the ObjectRenderer installed by LoadResourcesFS.
+type defaultObjectRenderer struct{}
+
+func (defaultObjectRenderer) DrawTile(r *Resources, t pixel.Target, obj *tmx.Object, tint pixel.RGBA) {
+	gid := tmx.TileInstance(obj.GID)
+	entry, ok := r.entries[r.resolveGID(gid.GID())]
+	if !ok {
+		return
+	}
+	data, ok := entry.data.Copy().(*pixel.TrianglesData)
+	if !ok {
+		return
+	}
+
+	if gid.FlippedDiagonally() {
+		for i := range *data {
+			(*data)[i].Position = diagonalFlipMatrix.Project((*data)[i].Position)
+		}
+	}
+	if gid.FlippedHorizontally() {
+		for i := range *data {
+			(*data)[i].Position = horizontalFlipMatrix.Project((*data)[i].Position)
+		}
+	}
+	if gid.FlippedVertically() {
+		for i := range *data {
+			(*data)[i].Position = verticalFlipMatrix.Project((*data)[i].Position)
+		}
+	}
+
+	// Tile objects anchor at their bottom-left corner and rotate about it.
+	origin := pixel.V(obj.X, -obj.Y)
+	rotate := pixel.IM.Rotated(pixel.ZV, -obj.Rotation*math.Pi/180)
+	offset := pixel.V(obj.Width/2, obj.Height/2)
+	for i := range *data {
+		(*data)[i].Position = rotate.Project((*data)[i].Position.Add(offset)).Add(origin)
+		(*data)[i].Color = tint
+	}
+
+	tris := t.MakeTriangles(data)
+	if tp, ok := tris.(pixel.TargetPicture); ok {
+		tris = tp.SetPicture(r.atlas.Picture(entry.page))
+	}
+	tris.Draw()
+}
+
+func (defaultObjectRenderer) DrawShape(r *Resources, t pixel.Target, obj *tmx.Object, tint pixel.RGBA) {
+	imd := imdraw.New(nil)
+	imd.Color = tint
+	origin := pixel.V(obj.X, -obj.Y)
+	switch {
+	case obj.Ellipse != nil:
+		imd.Push(origin.Add(pixel.V(obj.Width/2, -obj.Height/2)))
+		imd.Ellipse(pixel.V(obj.Width/2, obj.Height/2), 0)
+	case obj.Polygon != nil:
+		for _, p := range obj.Polygon.Points {
+			imd.Push(origin.Add(pixel.V(p.X, -p.Y)))
+		}
+		imd.Polygon(0)
+	case obj.Polyline != nil:
+		for _, p := range obj.Polyline.Points {
+			imd.Push(origin.Add(pixel.V(p.X, -p.Y)))
+		}
+		imd.Line(1)
+	default:
+		imd.Push(origin, origin.Add(pixel.V(obj.Width, -obj.Height)))
+		imd.Rectangle(0)
+	}
+	imd.Draw(t)
+}
+
+func (defaultObjectRenderer) DrawText(r *Resources, t pixel.Target, obj *tmx.Object, tint pixel.RGBA) {
+	if obj.Text == nil || r.textAtlas == nil {
+		return
+	}
+	origin := pixel.V(obj.X, -obj.Y-obj.Height)
+	txt := text.New(origin, r.textAtlas)
+	txt.Color = tint
+	fmt.Fprint(txt, obj.Text.Value)
+	txt.Draw(t, pixel.IM)
+}
+
+// objectTint looks for a custom "tint" property on obj (a hex color, with
+// or without a leading '#' and with or without an alpha channel) and
+// returns it, defaulting to fully opaque white.
+func objectTint(obj *tmx.Object) pixel.RGBA {
+	for _, p := range obj.Properties {
+		if !strings.EqualFold(p.Name, "tint") {
+			continue
+		}
+		if c, err := parseHexColor(p.Value); err == nil {
+			return c
+		}
+	}
+	return pixel.Alpha(1)
+}
+
+func parseHexColor(s string) (pixel.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 6 {
+		s = "ff" + s
+	}
+	if len(s) != 8 {
+		return pixel.RGBA{}, errors.Errorf("invalid color %q", s)
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return pixel.RGBA{}, errors.Wrapf(err, "invalid color %q", s)
+	}
+	return pixel.RGBA{
+		A: float64((v>>24)&0xff) / 255,
+		R: float64((v>>16)&0xff) / 255,
+		G: float64((v>>8)&0xff) / 255,
+		B: float64(v&0xff) / 255,
+	}, nil
+}
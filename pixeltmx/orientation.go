@@ -0,0 +1,234 @@
+package pixeltmx
+
+import (
+	"math"
+
+	"github.com/faiface/pixel"
+	"github.com/pkg/errors"
+)
+
+// Orientation identifies how a TMX map's tile grid is projected onto the
+// screen.
+type Orientation int
+
+// The orientations supported by the TMX format.
+const (
+	OrientationOrthogonal Orientation = iota
+	OrientationIsometric
+	OrientationStaggered
+	OrientationHexagonal
+)
+
+func parseOrientation(s string) (Orientation, error) {
+	switch s {
+	case "", "orthogonal":
+		return OrientationOrthogonal, nil
+	case "isometric":
+		return OrientationIsometric, nil
+	case "staggered":
+		return OrientationStaggered, nil
+	case "hexagonal":
+		return OrientationHexagonal, nil
+	default:
+		return OrientationOrthogonal, errors.Errorf("unknown map orientation %q", s)
+	}
+}
+
+// mapGeometry holds the map-wide dimensions needed to project tile (col, row)
+// coordinates into pixel space. It is populated once in LoadResources from
+// the parsed tmx.Map and consulted by every orientation's projection.
+type mapGeometry struct {
+	orientation   Orientation
+	cols          int
+	rows          int
+	tileWidth     int
+	tileHeight    int
+	staggerAxis   string
+	staggerIndex  string
+	hexSideLength int
+}
+
+// staggersAt reports whether the given row or column (depending on
+// staggerAxis) is a "staggered" line, i.e. shifted half a tile relative to
+// its neighbors.
+func (g mapGeometry) staggersAt(i int) bool {
+	if g.staggerIndex == "even" {
+		return i%2 == 0
+	}
+	// Tiled defaults staggerindex to "odd" when the attribute is omitted.
+	return i%2 != 0
+}
+
+// TileToScreen converts a tile (col, row) coordinate into the pixel position
+// of that tile's center, honoring the map's orientation. The result is in
+// the same coordinate space fillTileAndMod uses to place triangle data: Y
+// increases upward with row 0 nearest the top of the map.
+func (r *Resources) TileToScreen(col, row int) pixel.Vec {
+	switch r.geometry.orientation {
+	case OrientationIsometric:
+		return r.isometricToScreen(col, row)
+	case OrientationStaggered:
+		return r.staggeredToScreen(col, row)
+	case OrientationHexagonal:
+		return r.hexagonalToScreen(col, row)
+	default:
+		return r.orthogonalToScreen(col, row)
+	}
+}
+
+// ScreenToTile inverts TileToScreen, returning the (col, row) of the tile
+// under the given pixel position. It is intended for hit-testing, e.g.
+// translating a mouse cursor into the tile beneath it.
+func (r *Resources) ScreenToTile(p pixel.Vec) (int, int) {
+	switch r.geometry.orientation {
+	case OrientationIsometric:
+		return r.screenToIsometric(p)
+	case OrientationStaggered:
+		return r.screenToStaggered(p)
+	case OrientationHexagonal:
+		return r.screenToHexagonal(p)
+	default:
+		return r.screenToOrthogonal(p)
+	}
+}
+
+func (r *Resources) orthogonalToScreen(col, row int) pixel.Vec {
+	g := r.geometry
+	x := float64(col*g.tileWidth) + float64(g.tileWidth)/2
+	y := float64(g.rows-1-row)*float64(g.tileHeight) + float64(g.tileHeight)/2
+	return pixel.V(x, y)
+}
+
+func (r *Resources) screenToOrthogonal(p pixel.Vec) (int, int) {
+	g := r.geometry
+	col := int(p.X) / g.tileWidth
+	row := g.rows - 1 - int(p.Y)/g.tileHeight
+	return col, row
+}
+
+// isometricToScreen implements Tiled's diamond projection: columns run down
+// and to the right, rows run down and to the left.
+func (r *Resources) isometricToScreen(col, row int) pixel.Vec {
+	g := r.geometry
+	halfW := float64(g.tileWidth) / 2
+	halfH := float64(g.tileHeight) / 2
+	x := float64(g.rows)*halfW + float64(col-row)*halfW
+	y := float64(g.rows+g.cols)*halfH - float64(col+row)*halfH
+	return pixel.V(x, y)
+}
+
+func (r *Resources) screenToIsometric(p pixel.Vec) (int, int) {
+	g := r.geometry
+	halfW := float64(g.tileWidth) / 2
+	halfH := float64(g.tileHeight) / 2
+	x := p.X - float64(g.rows)*halfW
+	y := float64(g.rows+g.cols)*halfH - p.Y
+	col := int((x/halfW + y/halfH) / 2)
+	row := int((y/halfH - x/halfW) / 2)
+	return col, row
+}
+
+// staggeredToScreen implements Tiled's "staggered" orientation: an
+// isometric-looking grid built from offset rows or columns of rectangular
+// tiles, rather than a true diamond projection.
+func (r *Resources) staggeredToScreen(col, row int) pixel.Vec {
+	g := r.geometry
+	halfW := float64(g.tileWidth) / 2
+	halfH := float64(g.tileHeight) / 2
+	var x, y float64
+	if g.staggerAxis == "x" {
+		x = float64(col) * halfW
+		y = float64(g.rows-1-row) * float64(g.tileHeight)
+		if g.staggersAt(col) {
+			y += halfH
+		}
+	} else {
+		x = float64(col) * float64(g.tileWidth)
+		if g.staggersAt(row) {
+			x += halfW
+		}
+		y = float64(g.rows-1-row) * halfH
+	}
+	return pixel.V(x+halfW, y+halfH)
+}
+
+func (r *Resources) screenToStaggered(p pixel.Vec) (int, int) {
+	// The staggered grid is locally rectangular, so nearest-tile-center is a
+	// good enough approximation for hit-testing.
+	g := r.geometry
+	halfW, halfH := float64(g.tileWidth)/2, float64(g.tileHeight)/2
+	if g.staggerAxis == "x" {
+		col := int(math.Round(p.X/halfW - 1))
+		if g.staggersAt(col) {
+			p.Y -= halfH
+		}
+		row := g.rows - 1 - int(math.Round((p.Y-halfH)/float64(g.tileHeight)))
+		return col, row
+	}
+	row := g.rows - 1 - int(math.Round((p.Y-halfH)/halfH))
+	if g.staggersAt(row) {
+		p.X -= halfW
+	}
+	col := int(math.Round((p.X - halfW) / float64(g.tileWidth)))
+	return col, row
+}
+
+// hexagonalToScreen implements Tiled's hexagonal orientation, which is a
+// staggered grid where every other row/column is offset by half a tile, and
+// rows (or columns, for stagger axis x) are spaced by the configured hex side
+// length plus half of the remaining tile height (or width).
+func (r *Resources) hexagonalToScreen(col, row int) pixel.Vec {
+	g := r.geometry
+	halfW := float64(g.tileWidth) / 2
+	halfH := float64(g.tileHeight) / 2
+	var x, y float64
+	if g.staggerAxis == "x" {
+		sideOffset := (float64(g.tileWidth) - float64(g.hexSideLength)) / 2
+		colWidth := float64(g.hexSideLength) + sideOffset
+		x = float64(col) * colWidth
+		y = float64(g.rows-1-row) * float64(g.tileHeight)
+		if g.staggersAt(col) {
+			y += halfH
+		}
+	} else {
+		sideOffset := (float64(g.tileHeight) - float64(g.hexSideLength)) / 2
+		rowHeight := float64(g.hexSideLength) + sideOffset
+		x = float64(col) * float64(g.tileWidth)
+		if g.staggersAt(row) {
+			x += halfW
+		}
+		y = float64(g.rows-1-row) * rowHeight
+	}
+	return pixel.V(x+halfW, y+halfH)
+}
+
+// screenToHexagonal inverts hexagonalToScreen, using the same
+// hexSideLength-based column/row spacing as the forward projection rather
+// than the plain tileWidth/tileHeight spacing screenToStaggered uses.
+func (r *Resources) screenToHexagonal(p pixel.Vec) (int, int) {
+	g := r.geometry
+	halfW := float64(g.tileWidth) / 2
+	halfH := float64(g.tileHeight) / 2
+	x := p.X - halfW
+	y := p.Y - halfH
+	if g.staggerAxis == "x" {
+		sideOffset := (float64(g.tileWidth) - float64(g.hexSideLength)) / 2
+		colWidth := float64(g.hexSideLength) + sideOffset
+		col := int(math.Round(x / colWidth))
+		rowY := y
+		if g.staggersAt(col) {
+			rowY -= halfH
+		}
+		row := g.rows - 1 - int(math.Round(rowY/float64(g.tileHeight)))
+		return col, row
+	}
+	sideOffset := (float64(g.tileHeight) - float64(g.hexSideLength)) / 2
+	rowHeight := float64(g.hexSideLength) + sideOffset
+	row := g.rows - 1 - int(math.Round(y/rowHeight))
+	colX := x
+	if g.staggersAt(row) {
+		colX -= halfW
+	}
+	col := int(math.Round(colX / float64(g.tileWidth)))
+	return col, row
+}
@@ -0,0 +1,39 @@
+package pixeltmx
+
+import "testing"
+
+func TestOrientationRoundTrip(t *testing.T) {
+	cases := []struct {
+		name string
+		geo  mapGeometry
+	}{
+		{"orthogonal", mapGeometry{orientation: OrientationOrthogonal, cols: 10, rows: 10, tileWidth: 32, tileHeight: 32}},
+		{"isometric", mapGeometry{orientation: OrientationIsometric, cols: 10, rows: 10, tileWidth: 64, tileHeight: 32}},
+		{"staggered-x-odd", mapGeometry{orientation: OrientationStaggered, cols: 10, rows: 10, tileWidth: 32, tileHeight: 32, staggerAxis: "x", staggerIndex: "odd"}},
+		{"staggered-y-even", mapGeometry{orientation: OrientationStaggered, cols: 10, rows: 10, tileWidth: 32, tileHeight: 32, staggerAxis: "y", staggerIndex: "even"}},
+		{"hex-x", mapGeometry{orientation: OrientationHexagonal, cols: 10, rows: 10, tileWidth: 32, tileHeight: 28, staggerAxis: "x", staggerIndex: "odd", hexSideLength: 16}},
+		{"hex-y", mapGeometry{orientation: OrientationHexagonal, cols: 10, rows: 10, tileWidth: 28, tileHeight: 32, staggerAxis: "y", staggerIndex: "odd", hexSideLength: 16}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := &Resources{geometry: c.geo}
+			for row := 0; row < c.geo.rows; row++ {
+				for col := 0; col < c.geo.cols; col++ {
+					p := r.TileToScreen(col, row)
+					gotCol, gotRow := r.ScreenToTile(p)
+					if gotCol != col || gotRow != row {
+						t.Errorf("round trip (%d,%d) -> %v -> (%d,%d)", col, row, p, gotCol, gotRow)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestStaggersAtDefaultsToOdd(t *testing.T) {
+	g := mapGeometry{}
+	if !g.staggersAt(1) || g.staggersAt(0) {
+		t.Fatalf("expected unset staggerIndex to default to odd")
+	}
+}
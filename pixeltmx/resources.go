@@ -2,14 +2,13 @@ package pixeltmx
 
 import (
 	"image"
-	"os"
 
 	_ "image/png" // This is required for the parsing png resource files
 
 	"github.com/elliotmr/tmx"
 	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/text"
 	"github.com/pkg/errors"
-	"path/filepath"
 )
 
 type tileSetEntry struct {
@@ -17,25 +16,26 @@ type tileSetEntry struct {
 	frame    pixel.Rect
 	firstGID uint32
 	source   string
+	page     int
 }
 
 // Resources holds all the raw images and miscellaneous files required for
 // rendering a TMX map. This includes tilesets and tileset pictures, raw
 // images, object templates, etc.
 type Resources struct {
-	// TODO: add text atlas and template maps
-	path    string
-	entries map[uint32]tileSetEntry
-	images  map[string]pixel.Picture
+	loader     ResourceLoader
+	entries    map[uint32]tileSetEntry
+	images     map[string]pixel.Picture
+	geometry   mapGeometry
+	animations map[uint32]*tileAnimation
+	atlas      *Atlas
+	templates  map[string]*templateEntry
+	renderer   ObjectRenderer
+	textAtlas  *text.Atlas
 }
 
 func (r *Resources) loadImage(source string) (string, error) {
-	if filepath.IsAbs(source) {
-		source = filepath.Clean(source)
-	} else {
-		source = filepath.Join(r.path, source)
-	}
-	imageFile, err := os.Open(source)
+	imageFile, resolved, err := r.openResource(source)
 	if err != nil {
 		return "", errors.Wrap(err, "unable to open tileset image")
 	}
@@ -45,8 +45,43 @@ func (r *Resources) loadImage(source string) (string, error) {
 		return "", errors.Wrap(err, "unable to decode tileset image")
 	}
 	pic := pixel.PictureDataFromImage(tilesetImg)
-	r.images[source] = pic
-	return source, nil
+	r.images[resolved] = pic
+	return resolved, nil
+}
+
+// loadTileSet parses one tmx.TileSet's image into per-GID tileSetEntry
+// records and its tile animations. It is shared by the top-level map
+// tileset list and by tilesets embedded in object <template> files.
+func (r *Resources) loadTileSet(set *tmx.TileSet) error {
+	source, err := r.loadImage(set.Image.Source)
+	if err != nil {
+		return err
+	}
+	bounds := r.images[source].Bounds()
+	// tmx convention right -> down (origin top left), pixel convetion right -> up (origin bottom left)
+	// this means we have to flip the row index
+	rows := set.TileCount / set.Columns
+
+	for id := uint32(0); id < set.TileCount; id++ {
+		row := rows - id/set.Columns - 1
+		col := id % set.Columns
+		minX := float64(set.Margin + col*(set.TileWidth+set.Spacing))
+		minY := float64(set.Margin + row*(set.TileHeight+set.Spacing))
+		maxX := float64(set.Margin + col*(set.TileWidth+set.Spacing) + set.TileWidth)
+		maxY := float64(set.Margin + row*(set.TileHeight+set.Spacing) + set.TileHeight)
+		if minX < bounds.Min.X || minY < bounds.Min.Y || maxX > bounds.Max.X || maxY > bounds.Max.Y {
+			return errors.Errorf("tile %d bounds outside of texture bounds (%f, %f, %f, %f)", id, minX, minY, maxX, maxY)
+		}
+		frame := pixel.R(minX, minY, maxX, maxY)
+		r.entries[id+set.FirstGID] = tileSetEntry{
+			frame:    frame,
+			data:     createTriangleData(frame),
+			firstGID: set.FirstGID,
+			source:   source,
+		}
+	}
+	r.loadAnimations(set)
+	return nil
 }
 
 func (r *Resources) loadLayer(layer *tmx.Layer) error {
@@ -57,7 +92,15 @@ func (r *Resources) loadLayer(layer *tmx.Layer) error {
 			return err
 		}
 	}
-	// TODO: Load Templates
+
+	for _, obj := range layer.Objects {
+		if obj.Template == "" {
+			continue
+		}
+		if err := r.loadTemplate(obj.Template); err != nil {
+			return err
+		}
+	}
 
 	// walk the children recursively.
 	for _, child := range layer.Layers {
@@ -72,44 +115,45 @@ func (r *Resources) loadLayer(layer *tmx.Layer) error {
 // LoadResources searches through the tmx map tree and loads any resources found. If
 // the resources are located somewhere other than the current working directory, the
 // location should be supplied in the path string.
+//
+// LoadResources is a convenience wrapper around LoadResourcesFS for the
+// common case of resources living in an OS directory; use LoadResourcesFS
+// directly to load from an embed.FS, archive, or other virtual filesystem.
 func LoadResources(mapData *tmx.Map, path string) (*Resources, error) {
-	// TODO: figure out how to abstract the file system (maybe use Afero?)
-	if path == "" {
-		path = "."
+	return LoadResourcesFS(mapData, NewDirLoader(path))
+}
+
+// LoadResourcesFS searches through the tmx map tree and loads any resources
+// found, resolving and opening every image, template, and other referenced
+// file through loader.
+func LoadResourcesFS(mapData *tmx.Map, loader ResourceLoader) (*Resources, error) {
+	orientation, err := parseOrientation(mapData.Orientation)
+	if err != nil {
+		return nil, err
 	}
 	r := &Resources{
-		path:    path,
-		entries: make(map[uint32]tileSetEntry),
-		images:  make(map[string]pixel.Picture),
+		loader:     loader,
+		entries:    make(map[uint32]tileSetEntry),
+		images:     make(map[string]pixel.Picture),
+		animations: make(map[uint32]*tileAnimation),
+		atlas:      NewAtlas(),
+		templates:  make(map[string]*templateEntry),
+		renderer:   defaultObjectRenderer{},
+		geometry: mapGeometry{
+			orientation:   orientation,
+			cols:          mapData.Width,
+			rows:          mapData.Height,
+			tileWidth:     mapData.TileWidth,
+			tileHeight:    mapData.TileHeight,
+			staggerAxis:   mapData.StaggerAxis,
+			staggerIndex:  mapData.StaggerIndex,
+			hexSideLength: mapData.HexSideLength,
+		},
 	}
 	for _, set := range mapData.TileSets {
-		source, err := r.loadImage(set.Image.Source)
-		if err != nil {
+		if err := r.loadTileSet(set); err != nil {
 			return nil, err
 		}
-		bounds := r.images[source].Bounds()
-		// tmx convention right -> down (origin top left), pixel convetion right -> up (origin bottom left)
-		// this means we have to flip the row index
-		rows := set.TileCount / set.Columns
-
-		for id := uint32(0); id < set.TileCount; id++ {
-			row := rows - id/set.Columns - 1
-			col := id % set.Columns
-			minX := float64(set.Margin + col*(set.TileWidth+set.Spacing))
-			minY := float64(set.Margin + row*(set.TileHeight+set.Spacing))
-			maxX := float64(set.Margin + col*(set.TileWidth+set.Spacing) + set.TileWidth)
-			maxY := float64(set.Margin + row*(set.TileHeight+set.Spacing) + set.TileHeight)
-			if minX < bounds.Min.X || minY < bounds.Min.Y || maxX > bounds.Max.X || maxY > bounds.Max.Y {
-				return nil, errors.Errorf("tile %d bounds outside of texture bounds (%f, %f, %f, %f)", id, minX, minY, maxX, maxY)
-			}
-			frame := pixel.R(minX, minY, maxX, maxY)
-			r.entries[id+set.FirstGID] = tileSetEntry{
-				frame:    frame,
-				data:     createTriangleData(frame),
-				firstGID: set.FirstGID,
-				source:   source,
-			}
-		}
 	}
 
 	for _, l := range mapData.Layers {
@@ -118,19 +162,49 @@ func LoadResources(mapData *tmx.Map, path string) (*Resources, error) {
 			return nil, errors.Wrap(err, "unable to load resources")
 		}
 	}
+
+	if err := r.atlas.Pack(r.images); err != nil {
+		return nil, errors.Wrap(err, "unable to pack texture atlas")
+	}
+	r.remapToAtlas()
+
 	return r, nil
 }
 
+// remapToAtlas rewrites every entry's triangle Picture UVs (and records its
+// atlas page) to point into the packed atlas rather than its original
+// standalone image. Entry Position data is left untouched since it only
+// describes the tile's local quad shape, not where its texture lives.
+func (r *Resources) remapToAtlas() {
+	for gid, entry := range r.entries {
+		page, rect, ok := r.atlas.Placement(entry.source)
+		if !ok {
+			continue
+		}
+		bounds := r.images[entry.source].Bounds()
+		offset := entry.frame.Min.Sub(bounds.Min)
+		atlasFrame := pixel.Rect{Min: rect.Min.Add(offset), Max: rect.Min.Add(offset).Add(entry.frame.Size())}
+		for i := range *entry.data {
+			(*entry.data)[i].Picture = atlasFrame.Center().Add((*entry.data)[i].Position)
+		}
+		entry.page = page
+		r.entries[gid] = entry
+	}
+}
+
 var diagonalFlipMatrix = pixel.Matrix{0, -1, 1, 0, 0, 0}
 var horizontalFlipMatrix = pixel.Matrix{-1, 0, 0, 1, 0, 0}
 var verticalFlipMatrix = pixel.Matrix{1, 0, 0, -1, 0, 0}
 
-func (r *Resources) fillTileAndMod(tile tmx.TileInstance, rect pixel.Rect, rbga pixel.RGBA, t pixel.Triangles) {
-	_, exists := r.entries[tile.GID()]
+// fillTileAndMod writes the triangle data for the tile at (col, row) into t,
+// positioning it according to the map's orientation (see TileToScreen).
+func (r *Resources) fillTileAndMod(tile tmx.TileInstance, col, row int, rbga pixel.RGBA, t pixel.Triangles) {
+	gid := r.resolveGID(tile.GID())
+	_, exists := r.entries[gid]
 	if !exists {
 		return
 	}
-	data, ok := r.entries[tile.GID()].data.Copy().(*pixel.TrianglesData)
+	data, ok := r.entries[gid].data.Copy().(*pixel.TrianglesData)
 	if !ok {
 		return
 	}
@@ -153,8 +227,9 @@ func (r *Resources) fillTileAndMod(tile tmx.TileInstance, rect pixel.Rect, rbga
 		}
 	}
 
+	center := r.TileToScreen(col, row)
 	for i := range *data {
-		(*data)[i].Position = (*data)[i].Position.Add(rect.Center())
+		(*data)[i].Position = (*data)[i].Position.Add(center)
 		(*data)[i].Color = rbga
 	}
 	t.Update(data)